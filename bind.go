@@ -0,0 +1,319 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// BindErrorKind distinguishes why Bind/BindForm/BindMultipart failed, so
+// callers can answer differently (e.g. a 413 for an oversized body) without
+// string-matching the message.
+type BindErrorKind string
+
+const (
+	BindErrorMalformed   BindErrorKind = "malformed"
+	BindErrorContentType BindErrorKind = "content_type"
+	BindErrorTooLarge    BindErrorKind = "too_large"
+	BindErrorValidation  BindErrorKind = "validation"
+)
+
+// FieldError is one struct field that failed a validate tag rule.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// BindError is returned by Bind/BindForm/BindMultipart on failure. It's
+// also a valid JSON response body in its own right, e.g.
+// res.Status("400").Json(bindErr), giving clients a consistent error
+// envelope regardless of what went wrong.
+type BindError struct {
+	Kind    BindErrorKind `json:"kind"`
+	Message string        `json:"message"`
+	Fields  []FieldError  `json:"fields,omitempty"`
+}
+
+func (e *BindError) Error() string {
+	return e.Message
+}
+
+// defaultMultipartMemory is the cap passed to ParseMultipartForm when
+// Server.MaxBodyBytes isn't set.
+const defaultMultipartMemory = 32 << 20
+
+// Bind decodes the request body into a *T, picking JSON, form, or
+// multipart decoding based on the Content-Type header, then runs field
+// validation from `validate` struct tags. It honors Server.MaxBodyBytes and
+// returns a *BindError distinguishing malformed bodies, wrong content
+// types, oversized bodies, and validation failures.
+func Bind[T any](req *Request) (*T, error) {
+	mediaType, _, err := mime.ParseMediaType(req.httpRequest.Header.Get("Content-Type"))
+	if err != nil && req.httpRequest.Header.Get("Content-Type") != "" {
+		return nil, &BindError{Kind: BindErrorContentType, Message: fmt.Sprintf("bind: invalid Content-Type: %s", err)}
+	}
+
+	switch {
+	case mediaType == "" || mediaType == "application/json":
+		return bindJSON[T](req)
+	case mediaType == "application/x-www-form-urlencoded":
+		return BindForm[T](req)
+	case strings.HasPrefix(mediaType, "multipart/"):
+		return BindMultipart[T](req)
+	default:
+		return nil, &BindError{Kind: BindErrorContentType, Message: fmt.Sprintf("bind: unsupported content type %q", mediaType)}
+	}
+}
+
+func bindJSON[T any](req *Request) (*T, error) {
+	var result T
+	if err := json.NewDecoder(req.limitedBody()).Decode(&result); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return nil, &BindError{Kind: BindErrorTooLarge, Message: "bind: request body exceeds the configured limit"}
+		}
+		return nil, &BindError{Kind: BindErrorMalformed, Message: fmt.Sprintf("bind: malformed JSON body: %s", err)}
+	}
+	if fields := validateStruct(&result); len(fields) > 0 {
+		return nil, &BindError{Kind: BindErrorValidation, Message: "bind: validation failed", Fields: fields}
+	}
+	return &result, nil
+}
+
+// BindForm decodes an application/x-www-form-urlencoded body into a *T,
+// matching form keys against each field's `json` tag name, then validates
+// it the same way Bind does.
+func BindForm[T any](req *Request) (*T, error) {
+	req.httpRequest.Body = req.limitedBody()
+	if err := req.httpRequest.ParseForm(); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return nil, &BindError{Kind: BindErrorTooLarge, Message: "bind: request body exceeds the configured limit"}
+		}
+		return nil, &BindError{Kind: BindErrorMalformed, Message: fmt.Sprintf("bind: malformed form body: %s", err)}
+	}
+
+	var result T
+	if err := decodeValues(req.httpRequest.PostForm, &result); err != nil {
+		return nil, &BindError{Kind: BindErrorMalformed, Message: err.Error()}
+	}
+	if fields := validateStruct(&result); len(fields) > 0 {
+		return nil, &BindError{Kind: BindErrorValidation, Message: "bind: validation failed", Fields: fields}
+	}
+	return &result, nil
+}
+
+// BindMultipart decodes a multipart/form-data body into a *T the same way
+// BindForm does for its value fields; use Request.File to read uploaded
+// files by field name.
+func BindMultipart[T any](req *Request) (*T, error) {
+	maxMem := req.maxBodyBytes
+	if maxMem <= 0 || maxMem > defaultMultipartMemory {
+		maxMem = defaultMultipartMemory
+	}
+
+	req.httpRequest.Body = req.limitedBody()
+	if err := req.httpRequest.ParseMultipartForm(maxMem); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return nil, &BindError{Kind: BindErrorTooLarge, Message: "bind: request body exceeds the configured limit"}
+		}
+		return nil, &BindError{Kind: BindErrorMalformed, Message: fmt.Sprintf("bind: malformed multipart body: %s", err)}
+	}
+
+	var result T
+	if err := decodeValues(req.httpRequest.MultipartForm.Value, &result); err != nil {
+		return nil, &BindError{Kind: BindErrorMalformed, Message: err.Error()}
+	}
+	if fields := validateStruct(&result); len(fields) > 0 {
+		return nil, &BindError{Kind: BindErrorValidation, Message: "bind: validation failed", Fields: fields}
+	}
+	return &result, nil
+}
+
+// File returns the uploaded file under the given multipart form field,
+// along with its original filename.
+func (r *Request) File(name string) (io.ReadCloser, string, error) {
+	if r.httpRequest.MultipartForm == nil {
+		if err := r.httpRequest.ParseMultipartForm(defaultMultipartMemory); err != nil {
+			return nil, "", err
+		}
+	}
+	file, header, err := r.httpRequest.FormFile(name)
+	if err != nil {
+		return nil, "", err
+	}
+	return file, header.Filename, nil
+}
+
+// limitedBody returns the request body capped at maxBodyBytes, or the raw
+// body if no limit was configured.
+func (r *Request) limitedBody() io.ReadCloser {
+	if r.maxBodyBytes <= 0 {
+		return r.httpRequest.Body
+	}
+	return http.MaxBytesReader(nil, r.httpRequest.Body, r.maxBodyBytes)
+}
+
+func decodeValues(values map[string][]string, dst any) error {
+	rv := reflect.ValueOf(dst).Elem()
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		name := jsonFieldName(field)
+		raw, ok := values[name]
+		if !ok || len(raw) == 0 {
+			continue
+		}
+		if err := setFieldFromString(rv.Field(i), raw[0]); err != nil {
+			return fmt.Errorf("bind: field %q: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func setFieldFromString(fv reflect.Value, raw string) error {
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(raw)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return err
+		}
+		fv.SetInt(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(n)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		fv.SetBool(b)
+	default:
+		return fmt.Errorf("unsupported field type %s", fv.Kind())
+	}
+	return nil
+}
+
+func jsonFieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		name = field.Name
+	}
+	return name
+}
+
+// ValidatorFunc is a custom rule registered with RegisterValidator. It
+// receives the field's value and returns a non-nil error describing why
+// the value is invalid.
+type ValidatorFunc func(value any) error
+
+var validators = struct {
+	mu    sync.RWMutex
+	funcs map[string]ValidatorFunc
+}{funcs: make(map[string]ValidatorFunc)}
+
+// RegisterValidator adds a custom `validate` tag rule, e.g.
+// RegisterValidator("phone", func(v any) error { ... }) to support
+// `validate:"phone"`.
+func RegisterValidator(name string, fn ValidatorFunc) {
+	validators.mu.Lock()
+	defer validators.mu.Unlock()
+	validators.funcs[name] = fn
+}
+
+// validateStruct runs every field's `validate` tag rules against v,
+// collecting one FieldError per failing rule.
+func validateStruct(v any) []FieldError {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var errs []FieldError
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		tag := field.Tag.Get("validate")
+		if tag == "" {
+			continue
+		}
+		name := jsonFieldName(field)
+		for _, rule := range strings.Split(tag, ",") {
+			if err := applyRule(rule, rv.Field(i)); err != nil {
+				errs = append(errs, FieldError{Field: name, Message: err.Error()})
+			}
+		}
+	}
+	return errs
+}
+
+func applyRule(rule string, fv reflect.Value) error {
+	name, arg, _ := strings.Cut(rule, "=")
+	switch name {
+	case "required":
+		if fv.IsZero() {
+			return fmt.Errorf("is required")
+		}
+		return nil
+	case "min":
+		return applyMin(fv, arg)
+	case "max":
+		return applyMax(fv, arg)
+	default:
+		validators.mu.RLock()
+		fn, ok := validators.funcs[name]
+		validators.mu.RUnlock()
+		if !ok {
+			return nil
+		}
+		return fn(fv.Interface())
+	}
+}
+
+func applyMin(fv reflect.Value, arg string) error {
+	switch fv.Kind() {
+	case reflect.String:
+		n, _ := strconv.Atoi(arg)
+		if len(fv.String()) < n {
+			return fmt.Errorf("must be at least %s characters", arg)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, _ := strconv.ParseInt(arg, 10, 64)
+		if fv.Int() < n {
+			return fmt.Errorf("must be at least %s", arg)
+		}
+	}
+	return nil
+}
+
+func applyMax(fv reflect.Value, arg string) error {
+	switch fv.Kind() {
+	case reflect.String:
+		n, _ := strconv.Atoi(arg)
+		if len(fv.String()) > n {
+			return fmt.Errorf("must be at most %s characters", arg)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, _ := strconv.ParseInt(arg, 10, 64)
+		if fv.Int() > n {
+			return fmt.Errorf("must be at most %s", arg)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"mime/multipart"
+	"net/http/httptest"
+	"testing"
+)
+
+type bindTestUser struct {
+	Name string `json:"name" validate:"required,min=1,max=5"`
+	Age  int    `json:"age" validate:"required,min=18"`
+}
+
+func TestValidateStructCollectsFieldErrors(t *testing.T) {
+	u := bindTestUser{Name: "toolong", Age: 10}
+	errs := validateStruct(&u)
+	if len(errs) != 2 {
+		t.Fatalf("len(errs) = %d, want 2, got %+v", len(errs), errs)
+	}
+
+	byField := make(map[string]bool)
+	for _, e := range errs {
+		byField[e.Field] = true
+	}
+	if !byField["name"] || !byField["age"] {
+		t.Fatalf("expected errors on name and age, got %+v", errs)
+	}
+}
+
+func TestValidateStructPasses(t *testing.T) {
+	u := bindTestUser{Name: "ok", Age: 21}
+	if errs := validateStruct(&u); len(errs) != 0 {
+		t.Fatalf("expected no errors, got %+v", errs)
+	}
+}
+
+func TestBindMultipartEnforcesMaxBodyBytes(t *testing.T) {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	if err := w.WriteField("name", "hi"); err != nil {
+		t.Fatalf("WriteField: %v", err)
+	}
+	if err := w.WriteField("age", "20"); err != nil {
+		t.Fatalf("WriteField: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	httpReq := httptest.NewRequest("POST", "/", &body)
+	httpReq.Header.Set("Content-Type", w.FormDataContentType())
+
+	req := &Request{httpRequest: httpReq, maxBodyBytes: 4}
+	_, err := BindMultipart[bindTestUser](req)
+	if err == nil {
+		t.Fatal("expected an error for a body over the configured limit")
+	}
+	var bindErr *BindError
+	if !errors.As(err, &bindErr) {
+		t.Fatalf("error is not a *BindError: %v", err)
+	}
+	if bindErr.Kind != BindErrorTooLarge {
+		t.Fatalf("Kind = %q, want %q", bindErr.Kind, BindErrorTooLarge)
+	}
+}
+
+func TestBindMultipartDecodesWithinLimit(t *testing.T) {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	if err := w.WriteField("name", "hi"); err != nil {
+		t.Fatalf("WriteField: %v", err)
+	}
+	if err := w.WriteField("age", "20"); err != nil {
+		t.Fatalf("WriteField: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	httpReq := httptest.NewRequest("POST", "/", &body)
+	httpReq.Header.Set("Content-Type", w.FormDataContentType())
+
+	req := &Request{httpRequest: httpReq, maxBodyBytes: 1 << 20}
+	result, err := BindMultipart[bindTestUser](req)
+	if err != nil {
+		t.Fatalf("BindMultipart: %v", err)
+	}
+	if result.Name != "hi" || result.Age != 20 {
+		t.Fatalf("result = %+v, want {hi 20}", result)
+	}
+}
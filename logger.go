@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+)
+
+// Logger is the structured sink for the per-request access log emitted by
+// handleRequest. Implement it to wire in zerolog/zap/slog/whatever; kv is a
+// flat, alternating key/value list (as in slog) rather than a map, so
+// callers aren't forced to allocate one per request.
+type Logger interface {
+	Log(level, msg string, kv ...any)
+}
+
+// stdLogger is the Logger installed by default when NewServer isn't given
+// WithLogger; it prints one line per call to stdout.
+type stdLogger struct{}
+
+func (stdLogger) Log(level, msg string, kv ...any) {
+	fmt.Printf("[%s] %s", level, msg)
+	for i := 0; i+1 < len(kv); i += 2 {
+		fmt.Printf(" %v=%v", kv[i], kv[i+1])
+	}
+	fmt.Println()
+}
+
+// ServerOption configures a Server at construction time, e.g.
+// NewServer(WithLogger(myLogger)).
+type ServerOption func(*Server)
+
+// WithLogger installs logger as the Server's structured logger in place of
+// the default stdout logger.
+func WithLogger(logger Logger) ServerOption {
+	return func(s *Server) {
+		s.logger = logger
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/signal"
@@ -13,9 +14,9 @@ import (
 
 type User struct {
 	ID    int    `json:"id"`
-	Name  string `json:"name"`
-	Phone string `json:"phone"`
-	Age   int    `json:"age"`
+	Name  string `json:"name" validate:"required,min=1,max=120"`
+	Phone string `json:"phone" validate:"required,phone"`
+	Age   int    `json:"age" validate:"required,min=1"`
 }
 
 type UserRepository struct {
@@ -62,12 +63,33 @@ func main() {
 	app := NewServer()
 	userRepo := NewUserRepository()
 
+	app.Use(RequestID, Recover, CORS)
+	app.EnableMetrics("/metrics")
+
+	RegisterValidator("phone", func(v any) error {
+		phone, _ := v.(string)
+		if len(phone) != 10 {
+			return fmt.Errorf("must be a 10 digit phone number")
+		}
+		for _, c := range phone {
+			if c < '0' || c > '9' {
+				return fmt.Errorf("must contain only digits")
+			}
+		}
+		return nil
+	})
+
+	app.OnShutdown(func(ctx context.Context) error {
+		fmt.Println("Flushing user repository...")
+		return nil
+	})
+
 	shutdownChan := make(chan struct{})
 
 	app.Get("/get-all-users", func(req *Request, res *Response) {
 		users := userRepo.GetAll()
 		res.Status("200").Json(users)
-	})
+	}, Gzip)
 
 	app.Get("/get-user/:userId", func(req *Request, res *Response) {
 		userIdStr := req.PathParam("userId")
@@ -85,33 +107,76 @@ func main() {
 	})
 
 	app.Post("/add-student", func(req *Request, res *Response) {
-		user := Body[User](req)
-
-		if user == nil {
-			res.Status("400").Json(map[string]string{"error": "Invalid request body"})
+		user, err := Bind[User](req)
+		if err != nil {
+			var bindErr *BindError
+			if errors.As(err, &bindErr) {
+				res.Status("400").Json(bindErr)
+				return
+			}
+			res.Status("400").Json(map[string]string{"error": err.Error()})
 			return
 		}
 
-		if user.Name == "" {
-			res.Status("400").Json(map[string]string{"error": "Name is required"})
-			return
-		}
+		newUser := userRepo.Add(*user)
+		res.Status("201").Json(map[string]interface{}{
+			"message": "Student added successfully",
+			"user":    newUser,
+		})
+	})
 
-		if user.Phone == "" {
-			res.Status("400").Json(map[string]string{"error": "Phone is required"})
+	app.Get("/events", func(req *Request, res *Response) {
+		done, err := res.SSE(req)
+		if err != nil {
+			res.Status("500").Json(map[string]string{"error": err.Error()})
 			return
 		}
 
-		if user.Age <= 0 {
-			res.Status("400").Json(map[string]string{"error": "Invalid age"})
+		go func() {
+			ticker := time.NewTicker(time.Second)
+			defer ticker.Stop()
+			defer res.End()
+			for {
+				select {
+				case <-done:
+					return
+				case t := <-ticker.C:
+					if res.SendEvent("tick", t.Format(time.RFC3339), "") != nil {
+						return
+					}
+				}
+			}
+		}()
+	})
+
+	app.Get("/ws", func(req *Request, res *Response) {
+		ws, err := res.Upgrade(req)
+		if err != nil {
+			res.Status("400").Json(map[string]string{"error": err.Error()})
 			return
 		}
 
-		newUser := userRepo.Add(*user)
-		res.Status("201").Json(map[string]interface{}{
-			"message": "Student added successfully",
-			"user":    newUser,
-		})
+		go func() {
+			defer ws.Close()
+			for {
+				frame, err := ws.ReadMessage()
+				if err != nil {
+					return
+				}
+				switch frame.Opcode {
+				case wsOpText, wsOpBinary:
+					if ws.WriteText(frame.Data) != nil {
+						return
+					}
+				case wsOpPing:
+					if ws.Pong(frame.Data) != nil {
+						return
+					}
+				case wsOpClose:
+					return
+				}
+			}
+		}()
 	})
 
 	go func() {
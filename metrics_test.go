@@ -0,0 +1,25 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWriteExpositionSaturationIsBoundedByWorkerPool(t *testing.T) {
+	m := newMetrics()
+	var buf bytes.Buffer
+	// 5 in-flight requests (some still queued) against a worker pool with
+	// only 2 slots occupied out of a capacity of 2: saturation must come
+	// from the worker pool's own occupied/capacity, not in-flight, or it
+	// reads above the documented 0-1 range.
+	m.writeExposition(&buf, 5, 2, 2)
+
+	out := buf.String()
+	if !strings.Contains(out, "http_in_flight_requests 5\n") {
+		t.Fatalf("expected http_in_flight_requests 5, got:\n%s", out)
+	}
+	if !strings.Contains(out, "http_worker_pool_saturation 1.000000\n") {
+		t.Fatalf("expected http_worker_pool_saturation 1.000000, got:\n%s", out)
+	}
+}
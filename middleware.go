@@ -0,0 +1,162 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Middleware wraps a RouteHandler to add cross-cutting behavior such as
+// logging or recovery. Handlers passed to Server.Use/Get/Post/... are
+// composed right-to-left: the first middleware registered ends up as the
+// outermost layer and runs first, the last registered runs innermost,
+// closest to the route handler.
+type Middleware func(RouteHandler) RouteHandler
+
+// chain wraps handler with mw so that mw[0] is outermost and mw[len(mw)-1]
+// runs last before handler itself.
+func chain(handler RouteHandler, mw []Middleware) RouteHandler {
+	for i := len(mw) - 1; i >= 0; i-- {
+		handler = mw[i](handler)
+	}
+	return handler
+}
+
+// RouteGroup shares a path prefix and a middleware stack across a set of
+// routes, e.g. app.Group("/api").Use(Auth).Get("/users", listUsers).
+type RouteGroup struct {
+	server     *Server
+	prefix     string
+	middleware []Middleware
+}
+
+// Use adds middleware that applies to every route registered on the group.
+func (g *RouteGroup) Use(mw ...Middleware) *RouteGroup {
+	g.middleware = append(g.middleware, mw...)
+	return g
+}
+
+func (g *RouteGroup) Get(route string, handler RouteHandler, mw ...Middleware) {
+	g.server.mustAddRoute(http.MethodGet, g.prefix+route, handler, append(append([]Middleware{}, g.middleware...), mw...))
+}
+
+func (g *RouteGroup) Post(route string, handler RouteHandler, mw ...Middleware) {
+	g.server.mustAddRoute(http.MethodPost, g.prefix+route, handler, append(append([]Middleware{}, g.middleware...), mw...))
+}
+
+func (g *RouteGroup) Put(route string, handler RouteHandler, mw ...Middleware) {
+	g.server.mustAddRoute(http.MethodPut, g.prefix+route, handler, append(append([]Middleware{}, g.middleware...), mw...))
+}
+
+func (g *RouteGroup) Delete(route string, handler RouteHandler, mw ...Middleware) {
+	g.server.mustAddRoute(http.MethodDelete, g.prefix+route, handler, append(append([]Middleware{}, g.middleware...), mw...))
+}
+
+// Group returns a nested group whose prefix is appended to this group's
+// prefix and which inherits this group's middleware.
+func (g *RouteGroup) Group(prefix string) *RouteGroup {
+	return &RouteGroup{
+		server:     g.server,
+		prefix:     g.prefix + strings.TrimRight(prefix, "/"),
+		middleware: append([]Middleware{}, g.middleware...),
+	}
+}
+
+// Recover turns a panic inside a handler into a 500 response instead of
+// crashing the worker goroutine.
+func Recover(next RouteHandler) RouteHandler {
+	return func(req *Request, res *Response) {
+		defer func() {
+			if rec := recover(); rec != nil {
+				res.Status("500").Json(map[string]string{"error": fmt.Sprintf("internal server error: %v", rec)})
+			}
+		}()
+		next(req, res)
+	}
+}
+
+// RequestLogger prints one line per request with the method, path, status
+// and duration, once the handler has finished writing its response. It's a
+// lightweight, per-route alternative to the Server-wide structured access
+// log driven by the Logger interface.
+func RequestLogger(next RouteHandler) RouteHandler {
+	return func(req *Request, res *Response) {
+		start := time.Now()
+		next(req, res)
+		fmt.Printf("%s %s %d %s\n", req.httpRequest.Method, req.httpRequest.URL.Path, res.status, time.Since(start))
+	}
+}
+
+// CORS allows cross-origin requests from any origin and answers preflight
+// OPTIONS requests directly without invoking the handler.
+func CORS(next RouteHandler) RouteHandler {
+	return func(req *Request, res *Response) {
+		res.Header("Access-Control-Allow-Origin", "*")
+		res.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+		res.Header("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		if req.httpRequest.Method == http.MethodOptions {
+			res.Status("204").End()
+			return
+		}
+		next(req, res)
+	}
+}
+
+// Gzip compresses the response body when the client advertises gzip support
+// via Accept-Encoding. It installs itself as the Response's body sink, so
+// Write and Json transparently compress through it. It also disables
+// Write's own chunk framing: those bytes would land inside the gzip stream
+// instead of the HTTP transport, corrupting it, and net/http already
+// chunks the transfer on its own since no Content-Length is set.
+func Gzip(next RouteHandler) RouteHandler {
+	return func(req *Request, res *Response) {
+		if !strings.Contains(req.httpRequest.Header.Get("Accept-Encoding"), "gzip") {
+			next(req, res)
+			return
+		}
+		res.Header("Content-Encoding", "gzip")
+		res.Header("Vary", "Accept-Encoding")
+		gz := gzip.NewWriter(res.writer)
+		res.bodyWriter = gz
+		res.skipChunkFraming = true
+		defer gz.Close()
+		next(req, res)
+	}
+}
+
+type contextKey int
+
+const requestIDKey contextKey = iota
+
+// RequestID assigns each request an ID, reusing an inbound X-Request-Id
+// header if the caller supplied one, and exposes it on both the request
+// context and the X-Request-Id response header.
+func RequestID(next RouteHandler) RouteHandler {
+	return func(req *Request, res *Response) {
+		id := req.httpRequest.Header.Get("X-Request-Id")
+		if id == "" {
+			id = newRequestID()
+		}
+		req.WithContext(context.WithValue(req.Context(), requestIDKey, id))
+		res.Header("X-Request-Id", id)
+		next(req, res)
+	}
+}
+
+// RequestIDFromContext returns the request ID stashed by RequestID, or ""
+// if the middleware wasn't installed.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+func newRequestID() string {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
@@ -4,34 +4,64 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
 	"runtime"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 type RouteHandler = func (*Request, *Response)
 
-type Route struct {
-	path string
-	handler RouteHandler
-}
-
 type Server struct {
-	routes map[string][]Route
+	router *router
 	mu sync.RWMutex
 	server *http.Server
 	workerPool chan struct{}
+	middleware []Middleware
+	inFlight sync.WaitGroup
+	inFlightCount int64
+	shutdownHooks []func(context.Context) error
+	// MaxBodyBytes caps the size of request bodies Bind/BindForm/
+	// BindMultipart will read. Zero (the default) means unlimited.
+	MaxBodyBytes int64
+	metrics *metrics
+	logger Logger
 }
 
-func NewServer() *Server {
+func NewServer(opts ...ServerOption) *Server {
 	maxWorkers := runtime.NumCPU() * 100
-	return &Server{
-		routes: make(map[string][]Route),
+	s := &Server{
+		router: newRouter(),
 		workerPool : make(chan struct{}, maxWorkers),
+		metrics: newMetrics(),
+		logger: stdLogger{},
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Use registers global middleware that wraps every route on the server.
+// Middleware must be registered before the routes it should apply to;
+// routes added before a Use call won't see it. Composition is right-to-left:
+// the last middleware registered runs innermost, closest to the handler.
+func (s *Server) Use(mw ...Middleware) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.middleware = append(s.middleware, mw...)
+}
+
+// Group returns a RouteGroup that prefixes every route registered on it with
+// prefix and inherits the server's middleware plus whatever is added with
+// RouteGroup.Use.
+func (s *Server) Group(prefix string) *RouteGroup {
+	return &RouteGroup{server: s, prefix: strings.TrimRight(prefix, "/")}
 }
 
 func (s *Server) Listen (port uint16) error  {
@@ -48,89 +78,164 @@ func (s *Server) Listen (port uint16) error  {
 }
 
 func (s *Server) handleRequest(w http.ResponseWriter, r *http.Request) {
+	s.inFlight.Add(1)
+	atomic.AddInt64(&s.inFlightCount, 1)
+	defer func() {
+		atomic.AddInt64(&s.inFlightCount, -1)
+		s.inFlight.Done()
+	}()
+
 	s.workerPool <- struct{}{}
 	defer func() {
 		<-s.workerPool
 	}()
 
+	start := time.Now()
+
 	s.mu.RLock()
-	routes,ok := s.routes[r.Method]
+	handler, params, template, found := s.router.lookup(r.Method, r.URL.Path)
 	s.mu.RUnlock()
 
-	if !ok {
-		http.NotFound(w,r)
-		return
-	}
-
-	path := strings.Trim(r.URL.Path, "/")
-	pathParts := strings.Split(path, "/")
-
-	for _,route := range routes {
-		routeParts := strings.Split(strings.Trim(route.path, "/"), "/")
-		if len(routeParts) != len(pathParts) {
-			// route matching algorithm
-			continue
-		}
-
-		params := make(map[string]string)
-		match := true
-
-		for i, part := range routeParts {
-			if strings.HasPrefix(part, ":") {
-				params[part[1:]] = pathParts[i]
-			} else if part != pathParts[i] {
-				match = false
-				break
-			}
-		}
-		if match {
-			req := &Request{
-				httpRequest: r,
-				params:      params,
-			}
-			res := NewResponse(w)
-			route.handler(req, res)
+	if !found {
+		s.mu.RLock()
+		allowed := s.router.allowedMethods(r.URL.Path)
+		s.mu.RUnlock()
+		if len(allowed) > 0 {
+			w.Header().Set("Allow", strings.Join(allowed, ", "))
+			http.Error(w, "405 method not allowed", http.StatusMethodNotAllowed)
+			s.metrics.observe(r.Method, "", http.StatusMethodNotAllowed, time.Since(start), 0)
+			s.logger.Log("info", "request handled",
+				"method", r.Method,
+				"route", "",
+				"status", http.StatusMethodNotAllowed,
+				"duration_ms", time.Since(start).Milliseconds(),
+				"request_id", RequestIDFromContext(r.Context()),
+			)
 			return
 		}
+		http.NotFound(w, r)
+		s.metrics.observe(r.Method, "", http.StatusNotFound, time.Since(start), 0)
+		s.logger.Log("info", "request handled",
+			"method", r.Method,
+			"route", "",
+			"status", http.StatusNotFound,
+			"duration_ms", time.Since(start).Milliseconds(),
+			"request_id", RequestIDFromContext(r.Context()),
+		)
+		return
 	}
 
-	http.NotFound(w, r)
+	req := &Request{
+		httpRequest: r,
+		params:      params,
+		routeTemplate: template,
+		maxBodyBytes: s.MaxBodyBytes,
+	}
+	res := NewResponse(w)
+	handler(req, res)
+
+	duration := time.Since(start)
+	s.metrics.observe(r.Method, template, res.status, duration, uint64(res.bytesWritten))
+	s.logger.Log("info", "request handled",
+		"method", r.Method,
+		"route", template,
+		"status", res.status,
+		"duration_ms", duration.Milliseconds(),
+		"bytes", res.bytesWritten,
+		"request_id", RequestIDFromContext(req.Context()),
+	)
 }
 
-
-func (s *Server) addRoute(method, path string, handler RouteHandler) {
+// addRoute registers handler for method and path, wrapping it with mw plus
+// any server-wide middleware. It returns an error instead of registering the
+// route if path is ambiguous with one already registered on method.
+func (s *Server) addRoute(method, path string, handler RouteHandler, mw []Middleware) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	s.routes[method] = append(s.routes[method], Route{path: path, handler: handler})
+	wrapped := chain(handler, append(append([]Middleware{}, s.middleware...), mw...))
+	return s.router.addRoute(method, path, wrapped)
+}
+
+// mustAddRoute is addRoute for the Get/Post/Put/Delete helpers, which have no
+// error return of their own; a conflicting route is a programming error, so
+// it panics at registration time rather than surfacing silently at request
+// time, same as http.ServeMux.Handle does for duplicate patterns.
+func (s *Server) mustAddRoute(method, path string, handler RouteHandler, mw []Middleware) {
+	if err := s.addRoute(method, path, handler, mw); err != nil {
+		panic(err)
+	}
+}
 
+func (s *Server) Get(route string, handler RouteHandler, mw ...Middleware) {
+	s.mustAddRoute(http.MethodGet, route, handler, mw)
 }
 
-func (s *Server) Get(route string, handler RouteHandler ) {
-	s.addRoute(http.MethodGet, route, handler)
+func (s *Server) Post(route string, handler RouteHandler, mw ...Middleware) {
+	s.mustAddRoute(http.MethodPost, route, handler, mw)
 }
 
-func (s *Server) Post(route string, handler RouteHandler) {
-	s.addRoute(http.MethodPost, route, handler)
+func (s *Server) Put(route string, handler RouteHandler, mw ...Middleware) {
+	s.mustAddRoute(http.MethodPut, route, handler, mw)
 }
 
-func (s *Server) Put(route string, handler RouteHandler) {
-	s.addRoute(http.MethodPut, route, handler)
+func (s *Server) Delete(route string, handler RouteHandler, mw ...Middleware) {
+	s.mustAddRoute(http.MethodDelete, route, handler, mw)
 }
 
-func (s *Server) Delete(route string, handler RouteHandler) {
-	s.addRoute(http.MethodDelete, route, handler)
+func (s *Server) Any(route string, handler RouteHandler, mw ...Middleware) {
+	s.Get(route, handler, mw...)
+	s.Post(route, handler, mw...)
+	s.Put(route, handler, mw...)
+	s.Delete(route, handler, mw...)
+}
+
+// OnShutdown registers fn to run during Shutdown, after the listener has
+// stopped accepting new connections but before Shutdown returns. Hooks run
+// in registration order; the first one to return an error aborts the rest.
+func (s *Server) OnShutdown(fn func(context.Context) error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.shutdownHooks = append(s.shutdownHooks, fn)
 }
 
-func (s *Server) Any(route string, handler RouteHandler ) {
-	s.Get(route, handler)
-	s.Post(route, handler)
-	s.Put(route, handler)
-	s.Delete(route, handler)
+// InFlight reports the number of requests currently being handled,
+// including ones blocked waiting for a worker-pool slot. Useful for
+// readiness probes that want to drain before reporting unready.
+func (s *Server) InFlight() int {
+	return int(atomic.LoadInt64(&s.inFlightCount))
 }
 
+// Shutdown stops the listener, waits for in-flight requests to finish
+// (bounded by ctx), then runs the registered OnShutdown hooks. It returns
+// ctx.Err() if ctx is done before requests drain.
 func (s *Server) Shutdown(ctx context.Context) error {
-	return s.server.Shutdown(ctx)
+	if err := s.server.Shutdown(ctx); err != nil {
+		return err
+	}
+
+	drained := make(chan struct{})
+	go func() {
+		s.inFlight.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	s.mu.RLock()
+	hooks := append([]func(context.Context) error{}, s.shutdownHooks...)
+	s.mu.RUnlock()
+
+	for _, hook := range hooks {
+		if err := hook(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 
@@ -138,6 +243,20 @@ func (s *Server) Shutdown(ctx context.Context) error {
 type Request struct {
 	httpRequest *http.Request
 	params map[string]string
+	// routeTemplate is the route path the request matched, e.g.
+	// "/get-user/:userId", as opposed to the raw URL path.
+	routeTemplate string
+	// maxBodyBytes mirrors Server.MaxBodyBytes at the time the request was
+	// dispatched, so Bind/BindForm/BindMultipart can enforce it without
+	// needing a reference back to the Server.
+	maxBodyBytes int64
+}
+
+// RouteTemplate returns the registered route path the request matched, for
+// logging and metrics that need to aggregate by route rather than by raw
+// path.
+func (r *Request) RouteTemplate() string {
+	return r.routeTemplate
 }
 
 func (r *Request) Headers() map[string]any {
@@ -160,23 +279,47 @@ func (r *Request) PathParam(param string) string {
 	return r.params[param]
 }
 
-// Adjusting the Function definition
-func Body[T any](r *Request) *T {
-	var result T
-	err := json.NewDecoder(r.httpRequest.Body).Decode(&result)
-	if err != nil {
-		return nil
-	}
-	return &result
+// Context returns the request's context.Context, carrying values such as the
+// request ID set by the RequestID middleware.
+func (r *Request) Context() context.Context {
+	return r.httpRequest.Context()
+}
+
+// WithContext replaces the request's context, e.g. so middleware can thread
+// values like a request ID down to the handler.
+func (r *Request) WithContext(ctx context.Context) {
+	r.httpRequest = r.httpRequest.WithContext(ctx)
 }
 
 
 
+
 type Response struct {
 	writer http.ResponseWriter
 	headerWritten bool
 	status int
 	headers map[string]string
+	// bodyWriter is the sink written to for chunked body data. It's nil by
+	// default (writer is used directly) and can be swapped by middleware
+	// such as Gzip to transparently compress the response body.
+	bodyWriter io.Writer
+	// streaming is set by SSE once the response has switched to a
+	// streamed event framing; Write/End skip their own chunk framing
+	// since the caller is in control of every byte written from then on.
+	streaming bool
+	// skipChunkFraming disables Write/End's hand-rolled "%x\r\n...\r\n"
+	// chunk framing for responses where it would corrupt the body instead
+	// of merely being redundant, e.g. Gzip's compressed stream. The actual
+	// chunked Transfer-Encoding the client sees is still applied by
+	// net/http itself, since none of these paths set a Content-Length.
+	skipChunkFraming bool
+	// hijackedConn is set by SSE once it has hijacked the underlying
+	// connection, so End can release it when the handler is done
+	// with the stream.
+	hijackedConn net.Conn
+	// bytesWritten tracks the size of the body written through sink(), for
+	// the access log and metrics.
+	bytesWritten int64
 }
 
 func NewResponse(w http.ResponseWriter) *Response {
@@ -187,6 +330,28 @@ func NewResponse(w http.ResponseWriter) *Response {
 	}
 }
 
+// sink returns the writer body data is written to, honoring any wrapping
+// installed by middleware, and counting bytes as they pass through.
+func (r *Response) sink() io.Writer {
+	var w io.Writer = r.writer
+	if r.bodyWriter != nil {
+		w = r.bodyWriter
+	}
+	return &countingWriter{w: w, count: &r.bytesWritten}
+}
+
+// countingWriter tallies bytes written through it into count.
+type countingWriter struct {
+	w     io.Writer
+	count *int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	*c.count += int64(n)
+	return n, err
+}
+
 func (r *Response) Header(header, value string) *Response {
 	if !r.headerWritten {
 		r.headers[header] = value
@@ -210,20 +375,39 @@ func (r *Response) writeHeaders() {
 	}
 }
 
+// End finishes the response, writing headers if they haven't been sent yet.
+// For a streamed SSE response it also releases the hijacked connection, so
+// callers should call End once their event loop is done rather than leaving
+// the connection open indefinitely.
 func (r *Response) End() {
 	r.writeHeaders()
+	if r.hijackedConn != nil {
+		r.hijackedConn.Close()
+		return
+	}
+	if r.streaming || r.skipChunkFraming {
+		return
+	}
 	if r.headers["Transfer-Encoding"] == "chunked" {
-		fmt.Fprintf(r.writer, "0\r\n\r\n")
+		fmt.Fprintf(r.sink(), "0\r\n\r\n")
 	}
 }
 
 func (r *Response) Json(resp interface{}) error {
 	r.Header("Content-Type", "application/json")
 	r.writeHeaders()
-	return json.NewEncoder(r.writer).Encode(resp)
+	return json.NewEncoder(r.sink()).Encode(resp)
 }
 
 func (r *Response) Write(data []byte) *Response {
+	if r.streaming || r.skipChunkFraming {
+		if !r.headerWritten {
+			r.writeHeaders()
+		}
+		r.sink().Write(data)
+		return r
+	}
+
 	if !r.headerWritten {
 		r.Header("Transfer-Encoding", "chunked")
 		r.writeHeaders()
@@ -233,8 +417,9 @@ func (r *Response) Write(data []byte) *Response {
 		return r
 	}
 
-	fmt.Fprintf(r.writer, "%x\r\n", len(data))
-	r.writer.Write(data)
-	fmt.Fprint(r.writer, "\r\n")
+	w := r.sink()
+	fmt.Fprintf(w, "%x\r\n", len(data))
+	w.Write(data)
+	fmt.Fprint(w, "\r\n")
 	return r
 }
\ No newline at end of file
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// routeNode is one segment of a per-method radix trie. Each node has at most
+// one literal child per segment value, one ":param" child, and one
+// "*catchAll" child, mirroring how the route paths are written.
+type routeNode struct {
+	children map[string]*routeNode
+	paramChild *routeNode
+	paramName  string
+	catchAllChild *routeNode
+	catchAllName  string
+
+	handler    RouteHandler
+	template   string
+	hasHandler bool
+}
+
+func newRouteNode() *routeNode {
+	return &routeNode{children: make(map[string]*routeNode)}
+}
+
+// router holds one trie per HTTP method.
+type router struct {
+	trees map[string]*routeNode
+}
+
+func newRouter() *router {
+	return &router{trees: make(map[string]*routeNode)}
+}
+
+func splitPath(path string) []string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, "/")
+}
+
+// addRoute inserts handler at path for method, returning an error instead of
+// registering it if the path conflicts with an already-registered route
+// (e.g. a ":id" param clashing with an existing ":uid" at the same
+// position, or the exact same path registered twice).
+func (t *router) addRoute(method, path string, handler RouteHandler) error {
+	root, ok := t.trees[method]
+	if !ok {
+		root = newRouteNode()
+		t.trees[method] = root
+	}
+
+	node := root
+	for _, seg := range splitPath(path) {
+		switch {
+		case strings.HasPrefix(seg, "*"):
+			name := seg[1:]
+			if node.catchAllChild == nil {
+				node.catchAllChild = newRouteNode()
+				node.catchAllName = name
+			} else if node.catchAllName != name {
+				return fmt.Errorf("router: %s %s conflicts with existing catch-all *%s", method, path, node.catchAllName)
+			}
+			node = node.catchAllChild
+		case strings.HasPrefix(seg, ":"):
+			name := seg[1:]
+			if node.paramChild == nil {
+				node.paramChild = newRouteNode()
+				node.paramName = name
+			} else if node.paramName != name {
+				return fmt.Errorf("router: %s %s conflicts with existing param :%s", method, path, node.paramName)
+			}
+			node = node.paramChild
+		default:
+			child, ok := node.children[seg]
+			if !ok {
+				child = newRouteNode()
+				node.children[seg] = child
+			}
+			node = child
+		}
+	}
+
+	if node.hasHandler {
+		return fmt.Errorf("router: %s %s is already registered", method, path)
+	}
+	node.handler = handler
+	node.template = path
+	node.hasHandler = true
+	return nil
+}
+
+// lookup walks the trie for method once, returning the handler, the path
+// params collected along the way, and the route template it matched (for
+// logging/metrics, so callers aggregate by "/get-user/:userId" rather than
+// by raw path).
+func (t *router) lookup(method, path string) (handler RouteHandler, params map[string]string, template string, found bool) {
+	root, ok := t.trees[method]
+	if !ok {
+		return nil, nil, "", false
+	}
+
+	node, params, ok := matchNode(root, splitPath(path))
+	if !ok {
+		return nil, nil, "", false
+	}
+	return node.handler, params, node.template, true
+}
+
+// matchNode walks segments from node, trying the literal child first, then
+// the param child, then the catch-all child, and backtracking to the next
+// option whenever a branch it descended into turns out to be a dead end.
+// Without backtracking, a literal route (e.g. "/a/x/b") registered alongside
+// a param route at the same depth (e.g. "/a/:p/c") would shadow the param
+// route for any request the literal branch can't itself satisfy (e.g.
+// "/a/x/c"), since the trie has no way to know to back out once it commits
+// to the literal child.
+func matchNode(node *routeNode, segments []string) (*routeNode, map[string]string, bool) {
+	if len(segments) == 0 {
+		if !node.hasHandler {
+			return nil, nil, false
+		}
+		return node, nil, true
+	}
+
+	seg, rest := segments[0], segments[1:]
+
+	if child, ok := node.children[seg]; ok {
+		if match, params, ok := matchNode(child, rest); ok {
+			return match, params, true
+		}
+	}
+
+	if node.paramChild != nil {
+		if match, params, ok := matchNode(node.paramChild, rest); ok {
+			if params == nil {
+				params = make(map[string]string, len(rest)+1)
+			}
+			params[node.paramName] = seg
+			return match, params, true
+		}
+	}
+
+	if node.catchAllChild != nil && node.catchAllChild.hasHandler {
+		return node.catchAllChild, map[string]string{node.catchAllName: strings.Join(segments, "/")}, true
+	}
+
+	return nil, nil, false
+}
+
+// allowedMethods reports every method for which path matches a registered
+// route, so handleRequest can tell "not found" apart from "wrong method"
+// and answer the latter with a 405 and an Allow header.
+func (t *router) allowedMethods(path string) []string {
+	segments := splitPath(path)
+	var methods []string
+	for method, root := range t.trees {
+		if pathRegistered(root, segments) {
+			methods = append(methods, method)
+		}
+	}
+	sort.Strings(methods)
+	return methods
+}
+
+// pathRegistered reports whether segments matches some route registered
+// under root, using the same backtracking as matchNode so a literal dead
+// end falls back to a param/catch-all route the way lookup itself would.
+func pathRegistered(root *routeNode, segments []string) bool {
+	_, _, ok := matchNode(root, segments)
+	return ok
+}
@@ -0,0 +1,93 @@
+package main
+
+import "testing"
+
+func TestRouterBacktracksPastLiteralDeadEnd(t *testing.T) {
+	r := newRouter()
+	noop := func(*Request, *Response) {}
+
+	if err := r.addRoute("GET", "/a/x/b", noop); err != nil {
+		t.Fatalf("addRoute /a/x/b: %v", err)
+	}
+	if err := r.addRoute("GET", "/a/:p/c", noop); err != nil {
+		t.Fatalf("addRoute /a/:p/c: %v", err)
+	}
+
+	_, params, template, found := r.lookup("GET", "/a/x/c")
+	if !found {
+		t.Fatal("expected /a/x/c to fall back to /a/:p/c, got no match")
+	}
+	if template != "/a/:p/c" {
+		t.Fatalf("template = %q, want /a/:p/c", template)
+	}
+	if params["p"] != "x" {
+		t.Fatalf("params[p] = %q, want x", params["p"])
+	}
+
+	_, _, template, found = r.lookup("GET", "/a/x/b")
+	if !found || template != "/a/x/b" {
+		t.Fatalf("literal route /a/x/b still needs to win over the param route: found=%v template=%q", found, template)
+	}
+}
+
+func TestRouterAllowedMethodsBacktracksPastLiteralDeadEnd(t *testing.T) {
+	r := newRouter()
+	noop := func(*Request, *Response) {}
+
+	if err := r.addRoute("GET", "/a/x/b", noop); err != nil {
+		t.Fatalf("addRoute /a/x/b: %v", err)
+	}
+	if err := r.addRoute("GET", "/a/:p/c", noop); err != nil {
+		t.Fatalf("addRoute /a/:p/c: %v", err)
+	}
+
+	methods := r.allowedMethods("/a/x/c")
+	if len(methods) != 1 || methods[0] != "GET" {
+		t.Fatalf("allowedMethods(/a/x/c) = %v, want [GET]", methods)
+	}
+}
+
+func TestRouterCatchAll(t *testing.T) {
+	r := newRouter()
+	noop := func(*Request, *Response) {}
+
+	if err := r.addRoute("GET", "/static/*rest", noop); err != nil {
+		t.Fatalf("addRoute: %v", err)
+	}
+
+	_, params, _, found := r.lookup("GET", "/static/css/app.css")
+	if !found {
+		t.Fatal("expected catch-all route to match")
+	}
+	if params["rest"] != "css/app.css" {
+		t.Fatalf("params[rest] = %q, want css/app.css", params["rest"])
+	}
+}
+
+func TestRouterNoMatch(t *testing.T) {
+	r := newRouter()
+	noop := func(*Request, *Response) {}
+
+	if err := r.addRoute("GET", "/a/:p", noop); err != nil {
+		t.Fatalf("addRoute: %v", err)
+	}
+
+	if _, _, _, found := r.lookup("GET", "/a/b/c"); found {
+		t.Fatal("expected no match for a path longer than any registered route")
+	}
+	if _, _, _, found := r.lookup("POST", "/a/b"); found {
+		t.Fatal("expected no match for an unregistered method")
+	}
+}
+
+func TestRouterConflictingParamNames(t *testing.T) {
+	r := newRouter()
+	noop := func(*Request, *Response) {}
+
+	if err := r.addRoute("GET", "/users/:id", noop); err != nil {
+		t.Fatalf("addRoute: %v", err)
+	}
+	if err := r.addRoute("GET", "/users/:uid", noop); err == nil {
+		t.Fatal("expected a conflicting param name to be rejected")
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// SSE switches the response to Server-Sent Events by hijacking the
+// underlying connection, the same way Upgrade does for WebSocket: a
+// long-lived stream can't be served by writing through the recycled
+// http.ResponseWriter after the route handler returns, since net/http is
+// free to reuse or close it for the next request on that connection the
+// moment the handler function returns. Hijacking hands the event loop a raw
+// net.Conn it owns independently of handleRequest's lifecycle, so the
+// handler can still return immediately — freeing the worker-pool slot
+// acquired in handleRequest — while the goroutine it started keeps writing
+// events.
+//
+// The returned channel closes once the client disconnects, so the event
+// loop knows when to stop; the handler should call Response.End when it
+// does, to release the connection.
+//
+// Like writeHeaders, SSE sends whatever is in r.headers at call time, so
+// headers set by middleware or the handler before calling SSE — CORS's
+// Access-Control-Allow-Origin, RequestID's X-Request-Id, and so on — still
+// reach the client instead of being silently dropped by the hand-rolled
+// header block hijacking requires.
+func (r *Response) SSE(req *Request) (<-chan struct{}, error) {
+	hijacker, ok := r.writer.(http.Hijacker)
+	if !ok {
+		return nil, fmt.Errorf("response: streaming unsupported by underlying ResponseWriter")
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, fmt.Errorf("response: hijack: %w", err)
+	}
+
+	r.Header("Content-Type", "text/event-stream")
+	r.Header("Cache-Control", "no-cache")
+	r.Header("Connection", "keep-alive")
+
+	var head strings.Builder
+	fmt.Fprintf(&head, "HTTP/1.1 %d %s\r\n", r.status, http.StatusText(r.status))
+	for k, v := range r.headers {
+		fmt.Fprintf(&head, "%s: %s\r\n", k, v)
+	}
+	head.WriteString("\r\n")
+
+	if _, err := rw.WriteString(head.String()); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if err := rw.Flush(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	r.headerWritten = true
+	r.streaming = true
+	r.bodyWriter = rw
+	r.hijackedConn = conn
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		// SSE is one-way traffic; any read returning at all (data the
+		// client has no business sending, or EOF/an error) means the
+		// connection is gone.
+		var b [1]byte
+		conn.Read(b[:])
+	}()
+	return done, nil
+}
+
+// SendEvent writes one SSE event. event and id are optional — pass "" to
+// omit them. SendEvent flushes immediately so the client receives the event
+// as soon as it's written, not whenever the underlying buffer fills.
+func (r *Response) SendEvent(event, data, id string) error {
+	if !r.streaming {
+		return fmt.Errorf("response: SendEvent called before SSE")
+	}
+
+	var b strings.Builder
+	if id != "" {
+		fmt.Fprintf(&b, "id: %s\n", id)
+	}
+	if event != "" {
+		fmt.Fprintf(&b, "event: %s\n", event)
+	}
+	for _, line := range strings.Split(data, "\n") {
+		fmt.Fprintf(&b, "data: %s\n", line)
+	}
+	b.WriteString("\n")
+
+	if _, err := r.sink().Write([]byte(b.String())); err != nil {
+		return err
+	}
+
+	if f, ok := r.bodyWriter.(interface{ Flush() error }); ok {
+		return f.Flush()
+	}
+	if flusher, ok := r.writer.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type hijackableResponseWriter struct {
+	http.ResponseWriter
+	conn net.Conn
+}
+
+func (h *hijackableResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	rw := bufio.NewReadWriter(bufio.NewReader(h.conn), bufio.NewWriter(h.conn))
+	return h.conn, rw, nil
+}
+
+func TestSSESendsHeadersSetBeforeHijack(t *testing.T) {
+	server, client := net.Pipe()
+	t.Cleanup(func() {
+		server.Close()
+		client.Close()
+	})
+
+	w := &hijackableResponseWriter{ResponseWriter: httptest.NewRecorder(), conn: server}
+	res := NewResponse(w)
+	res.Header("X-Request-Id", "abc123")
+	res.Header("Access-Control-Allow-Origin", "*")
+
+	req := &Request{httpRequest: httptest.NewRequest("GET", "/events", nil)}
+
+	headRead := make(chan string, 1)
+	go func() {
+		buf := make([]byte, 4096)
+		n, _ := client.Read(buf)
+		headRead <- string(buf[:n])
+	}()
+
+	if _, err := res.SSE(req); err != nil {
+		t.Fatalf("SSE: %v", err)
+	}
+
+	head := <-headRead
+	for _, want := range []string{
+		"X-Request-Id: abc123\r\n",
+		"Access-Control-Allow-Origin: *\r\n",
+		"Content-Type: text/event-stream\r\n",
+	} {
+		if !strings.Contains(head, want) {
+			t.Fatalf("expected %q in SSE response head, got:\n%s", want, head)
+		}
+	}
+}
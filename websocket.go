@@ -0,0 +1,216 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// websocketGUID is the magic value RFC 6455 §1.3 has clients and servers
+// concatenate with Sec-WebSocket-Key to derive Sec-WebSocket-Accept.
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+type wsOpcode byte
+
+const (
+	wsOpContinuation wsOpcode = 0x0
+	wsOpText         wsOpcode = 0x1
+	wsOpBinary       wsOpcode = 0x2
+	wsOpClose        wsOpcode = 0x8
+	wsOpPing         wsOpcode = 0x9
+	wsOpPong         wsOpcode = 0xA
+)
+
+// defaultMaxFrameSize caps a single frame's payload when WebSocket.MaxFrameSize
+// isn't set, so a client declaring an enormous length in the frame header
+// can't force ReadMessage into an equally enormous allocation before any of
+// the payload has even been read.
+const defaultMaxFrameSize = 1 << 20 // 1 MiB
+
+// WebSocket is a minimal RFC 6455 connection returned by Response.Upgrade.
+// It handles whole text/binary/ping/pong/close frames; it does not support
+// fragmented messages or extensions.
+type WebSocket struct {
+	conn net.Conn
+	rw   *bufio.ReadWriter
+	// MaxFrameSize caps the payload length ReadMessage will allocate for a
+	// single incoming frame. Zero (the default returned by Upgrade) means
+	// defaultMaxFrameSize.
+	MaxFrameSize int64
+}
+
+// Frame is one message read off the wire by ReadMessage.
+type Frame struct {
+	Opcode wsOpcode
+	Data   []byte
+}
+
+// Upgrade performs the WebSocket handshake against req and, on success,
+// hijacks the underlying connection and hands back a WebSocket for framing
+// reads and writes. As with SSE, handlers should start their read/write loop
+// in its own goroutine and return immediately so the worker-pool slot
+// acquired in handleRequest is freed for the next request.
+func (r *Response) Upgrade(req *Request) (*WebSocket, error) {
+	hr := req.httpRequest
+	if !strings.EqualFold(hr.Header.Get("Upgrade"), "websocket") {
+		return nil, fmt.Errorf("websocket: missing \"Upgrade: websocket\" header")
+	}
+	if hr.Header.Get("Sec-WebSocket-Version") != "13" {
+		return nil, fmt.Errorf("websocket: unsupported Sec-WebSocket-Version %q", hr.Header.Get("Sec-WebSocket-Version"))
+	}
+	key := hr.Header.Get("Sec-WebSocket-Key")
+	if key == "" {
+		return nil, fmt.Errorf("websocket: missing Sec-WebSocket-Key header")
+	}
+
+	hijacker, ok := r.writer.(http.Hijacker)
+	if !ok {
+		return nil, fmt.Errorf("websocket: hijacking not supported by underlying ResponseWriter")
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, fmt.Errorf("websocket: hijack: %w", err)
+	}
+
+	handshake := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + acceptKey(key) + "\r\n\r\n"
+	if _, err := rw.WriteString(handshake); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if err := rw.Flush(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	r.headerWritten = true
+	return &WebSocket{conn: conn, rw: rw}, nil
+}
+
+func acceptKey(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key))
+	h.Write([]byte(websocketGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// WriteText sends data as a single unfragmented text frame.
+func (ws *WebSocket) WriteText(data []byte) error {
+	return ws.writeFrame(wsOpText, data)
+}
+
+// WriteBinary sends data as a single unfragmented binary frame.
+func (ws *WebSocket) WriteBinary(data []byte) error {
+	return ws.writeFrame(wsOpBinary, data)
+}
+
+// Ping sends a ping control frame.
+func (ws *WebSocket) Ping(data []byte) error {
+	return ws.writeFrame(wsOpPing, data)
+}
+
+// Pong sends a pong control frame, typically in reply to a Ping read via
+// ReadMessage.
+func (ws *WebSocket) Pong(data []byte) error {
+	return ws.writeFrame(wsOpPong, data)
+}
+
+// Close sends a close frame and releases the underlying connection.
+func (ws *WebSocket) Close() error {
+	ws.writeFrame(wsOpClose, nil)
+	return ws.conn.Close()
+}
+
+// writeFrame sends a single, unfragmented, unmasked frame. Servers must not
+// mask frames they send (RFC 6455 §5.1).
+func (ws *WebSocket) writeFrame(opcode wsOpcode, data []byte) error {
+	header := make([]byte, 0, 10)
+	header = append(header, 0x80|byte(opcode)) // FIN set, no fragmentation
+
+	n := len(data)
+	switch {
+	case n <= 125:
+		header = append(header, byte(n))
+	case n <= 0xFFFF:
+		header = append(header, 126)
+		var size [2]byte
+		binary.BigEndian.PutUint16(size[:], uint16(n))
+		header = append(header, size[:]...)
+	default:
+		header = append(header, 127)
+		var size [8]byte
+		binary.BigEndian.PutUint64(size[:], uint64(n))
+		header = append(header, size[:]...)
+	}
+
+	if _, err := ws.rw.Write(header); err != nil {
+		return err
+	}
+	if _, err := ws.rw.Write(data); err != nil {
+		return err
+	}
+	return ws.rw.Flush()
+}
+
+// ReadMessage blocks for the next frame from the client and unmasks it per
+// RFC 6455 §5.3 (clients must mask every frame they send).
+func (ws *WebSocket) ReadMessage() (*Frame, error) {
+	head := make([]byte, 2)
+	if _, err := io.ReadFull(ws.rw, head); err != nil {
+		return nil, err
+	}
+
+	opcode := wsOpcode(head[0] & 0x0F)
+	masked := head[1]&0x80 != 0
+	length := uint64(head[1] & 0x7F)
+
+	switch length {
+	case 126:
+		ext := make([]byte, 2)
+		if _, err := io.ReadFull(ws.rw, ext); err != nil {
+			return nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext))
+	case 127:
+		ext := make([]byte, 8)
+		if _, err := io.ReadFull(ws.rw, ext); err != nil {
+			return nil, err
+		}
+		length = binary.BigEndian.Uint64(ext)
+	}
+
+	maxFrameSize := ws.MaxFrameSize
+	if maxFrameSize <= 0 {
+		maxFrameSize = defaultMaxFrameSize
+	}
+	if length > uint64(maxFrameSize) {
+		return nil, fmt.Errorf("websocket: frame length %d exceeds max frame size %d", length, maxFrameSize)
+	}
+
+	var maskKey [4]byte
+	if masked {
+		if _, err := io.ReadFull(ws.rw, maskKey[:]); err != nil {
+			return nil, err
+		}
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(ws.rw, payload); err != nil {
+		return nil, err
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= maskKey[i%4]
+		}
+	}
+
+	return &Frame{Opcode: opcode, Data: payload}, nil
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+)
+
+func newTestWebSocket(t *testing.T) (*WebSocket, net.Conn) {
+	t.Helper()
+	server, client := net.Pipe()
+	t.Cleanup(func() {
+		server.Close()
+		client.Close()
+	})
+	ws := &WebSocket{
+		conn: server,
+		rw:   bufio.NewReadWriter(bufio.NewReader(server), bufio.NewWriter(server)),
+	}
+	return ws, client
+}
+
+// maskFrame builds a masked client->server frame the way RFC 6455 §5.3
+// requires every frame a client sends to be masked.
+func maskFrame(opcode wsOpcode, payload []byte) []byte {
+	masked := make([]byte, len(payload))
+	var key [4]byte
+	// A non-zero, non-trivial key makes sure unmasking is actually tested,
+	// not just XOR-with-zero.
+	key = [4]byte{0x12, 0x34, 0x56, 0x78}
+	for i, b := range payload {
+		masked[i] = b ^ key[i%4]
+	}
+
+	frame := []byte{0x80 | byte(opcode)}
+	switch {
+	case len(payload) <= 125:
+		frame = append(frame, 0x80|byte(len(payload)))
+	case len(payload) <= 0xFFFF:
+		frame = append(frame, 0x80|126)
+		var size [2]byte
+		binary.BigEndian.PutUint16(size[:], uint16(len(payload)))
+		frame = append(frame, size[:]...)
+	}
+	frame = append(frame, key[:]...)
+	frame = append(frame, masked...)
+	return frame
+}
+
+func TestWebSocketReadMessageUnmasksClientFrame(t *testing.T) {
+	ws, client := newTestWebSocket(t)
+
+	want := []byte("hello")
+	go func() {
+		client.Write(maskFrame(wsOpText, want))
+	}()
+
+	frame, err := ws.ReadMessage()
+	if err != nil {
+		t.Fatalf("ReadMessage: %v", err)
+	}
+	if frame.Opcode != wsOpText {
+		t.Fatalf("opcode = %v, want wsOpText", frame.Opcode)
+	}
+	if string(frame.Data) != string(want) {
+		t.Fatalf("data = %q, want %q", frame.Data, want)
+	}
+}
+
+func TestWebSocketReadMessageRejectsOversizedFrame(t *testing.T) {
+	ws, client := newTestWebSocket(t)
+	ws.MaxFrameSize = 1024
+
+	go func() {
+		// FIN+text opcode, masked with the 127 (64-bit extended length)
+		// marker, declaring a length far past MaxFrameSize. No mask key or
+		// payload follows: ReadMessage must reject this from the header
+		// alone, before attempting to read or allocate anything sized by
+		// the declared length.
+		head := []byte{0x80 | byte(wsOpText), 0x80 | 127}
+		var size [8]byte
+		binary.BigEndian.PutUint64(size[:], 1<<62)
+		client.Write(append(head, size[:]...))
+	}()
+
+	if _, err := ws.ReadMessage(); err == nil {
+		t.Fatal("expected ReadMessage to reject a frame declaring a length over MaxFrameSize")
+	}
+}
+
+func TestWebSocketWriteTextIsUnmasked(t *testing.T) {
+	ws, client := newTestWebSocket(t)
+
+	done := make(chan error, 1)
+	go func() { done <- ws.WriteText([]byte("hi")) }()
+
+	reader := bufio.NewReader(client)
+	frame := make([]byte, 4) // FIN/opcode byte + length byte + 2-byte payload "hi"
+	if _, err := io.ReadFull(reader, frame); err != nil {
+		t.Fatalf("read frame: %v", err)
+	}
+	if frame[0] != 0x80|byte(wsOpText) {
+		t.Fatalf("fin/opcode byte = %#x, want FIN+text", frame[0])
+	}
+	if frame[1]&0x80 != 0 {
+		t.Fatal("server-to-client frame must not be masked (RFC 6455 §5.1)")
+	}
+	if frame[1]&0x7F != 2 {
+		t.Fatalf("payload length = %d, want 2", frame[1]&0x7F)
+	}
+	if string(frame[2:]) != "hi" {
+		t.Fatalf("payload = %q, want hi", frame[2:])
+	}
+
+	if err := <-done; err != nil {
+		t.Fatalf("WriteText: %v", err)
+	}
+}